@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// generatedHeaderTimestamp matches generateHeader's "DO NOT EDIT" line. It
+// exists so that, if generateHeader is ever changed to stamp that line with
+// a timestamp, checkMode keeps comparing the rest of the file instead of
+// flagging every run as stale.
+var generatedHeaderTimestamp = regexp.MustCompile(`(?m)^(// Code generated by kitgen\. DO NOT EDIT\.).*$`)
+
+// checkMode compares generated, the in-memory output this run would have
+// written to outputName, against what's already on disk. Both sides are
+// gofmt-normalized before comparing, so formatting-only differences (e.g.
+// between Go versions) don't trip a false positive. It logs a unified diff
+// and exits non-zero when they differ, and does not touch outputName.
+func checkMode(outputName string, generated []byte) {
+	existing, err := ioutil.ReadFile(outputName)
+	if err != nil {
+		log.Fatalf("%s is missing; run without -check to generate it: %s", outputName, err)
+	}
+
+	want, err := normalize(generated)
+	if err != nil {
+		log.Fatalf("formatting generated output: %s", err)
+	}
+
+	have, err := normalize(existing)
+	if err != nil {
+		log.Fatalf("formatting %s: %s", outputName, err)
+	}
+
+	if bytes.Equal(want, have) {
+		return
+	}
+
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(have)),
+		B:        difflib.SplitLines(string(want)),
+		FromFile: outputName,
+		ToFile:   outputName + " (generated)",
+		Context:  3,
+	}
+
+	text, _ := difflib.GetUnifiedDiffString(diff)
+
+	fmt.Fprint(os.Stderr, text)
+
+	log.Fatalf("%s is out of date; re-run %s to regenerate it", outputName, appName)
+}
+
+// normalize gofmt's src and strips the generated-header timestamp, if any.
+func normalize(src []byte) ([]byte, error) {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return generatedHeaderTimestamp.ReplaceAll(formatted, []byte("$1")), nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// runInit implements the `kit init` subcommand: it scans the current
+// package for interfaces carrying a `+kit:` decorator comment and writes a
+// starter kit.yaml declaring them, so a team can start from a config that
+// already matches their tree instead of hand-writing the types: list.
+func runInit() {
+	generator := new(Generator)
+
+	if err := generator.parse([]string{"."}); err != nil {
+		log.Fatalf("scanning package: %s", err)
+	}
+
+	cfg := &Config{Packages: []string{"."}, Suffix: defaultSuffix}
+
+	for _, pkg := range generator.pkgs {
+		for _, file := range pkg.Syntax {
+			cmap := ast.NewCommentMap(pkg.Fset, file, file.Comments)
+
+			ast.Inspect(file, func(node ast.Node) bool {
+				decl, ok := node.(*ast.GenDecl)
+				if !ok || decl.Tok != token.TYPE {
+					return true
+				}
+
+				for _, spec := range decl.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+
+					if _, isInterface := typeSpec.Type.(*ast.InterfaceType); !isInterface {
+						continue
+					}
+
+					if !hasKitComment(decl.Doc) && !anyHasKitComment(cmap[typeSpec]) {
+						continue
+					}
+
+					cfg.Types = append(cfg.Types, TypeConfig{Name: typeSpec.Name.Name})
+				}
+
+				return true
+			})
+		}
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		log.Fatalf("marshaling config: %s", err)
+	}
+
+	if err := ioutil.WriteFile(defaultConfigName, data, 0644); err != nil {
+		log.Fatalf("writing %s: %s", defaultConfigName, err)
+	}
+
+	log.Printf("wrote %s with %d decorated type(s)", defaultConfigName, len(cfg.Types))
+
+	os.Exit(0)
+}
+
+func hasKitComment(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+
+	for _, comment := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if strings.HasPrefix(text, kitCommentPrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func anyHasKitComment(groups []*ast.CommentGroup) bool {
+	for _, group := range groups {
+		if hasKitComment(group) {
+			return true
+		}
+	}
+
+	return false
+}
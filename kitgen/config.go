@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultSuffix     = "kit"
+	defaultConfigName = "kit.yaml"
+)
+
+// defaultConfigNames are tried, in order, when -config isn't given.
+var defaultConfigNames = []string{defaultConfigName, "kit.yml", ".kit.toml"}
+
+// Config is the contents of kit.yaml (or .kit.toml), the file-driven
+// alternative to passing a single -type flag. It mirrors the shape of
+// gqlgen's config: a list of sources, per-type overrides, template search
+// paths, and a mapping from user-defined names to the Go types templates
+// should render for them.
+type Config struct {
+	// Packages lists the packages or files to load, same syntax as the
+	// positional arguments kit accepts on the command line.
+	Packages []string `yaml:"packages,omitempty" toml:"packages,omitempty"`
+
+	// Suffix overrides the default output file suffix ("kit").
+	Suffix string `yaml:"suffix,omitempty" toml:"suffix,omitempty"`
+
+	// TemplateDirs are additional on-disk template roots searched before
+	// the embedded templates.
+	TemplateDirs []string `yaml:"template_dirs,omitempty" toml:"template_dirs,omitempty"`
+
+	// Types lists the decorated interfaces to generate, with optional
+	// per-type overrides.
+	Types []TypeConfig `yaml:"types,omitempty" toml:"types,omitempty"`
+
+	// Models maps a user-defined type name, as referenced from a
+	// decorator's options, to the Go type templates should render for it.
+	Models map[string]string `yaml:"models,omitempty" toml:"models,omitempty"`
+}
+
+// TypeConfig is one entry of the `types:` list in kit.yaml.
+type TypeConfig struct {
+	Name string `yaml:"name" toml:"name"`
+
+	// Output overrides the default "<type>_<suffix>.go" naming pattern
+	// for this type.
+	Output string `yaml:"output,omitempty" toml:"output,omitempty"`
+
+	// Decorators overrides which template is used for a given decorator
+	// name, keyed by decorator name.
+	Decorators map[string]string `yaml:"decorators,omitempty" toml:"decorators,omitempty"`
+}
+
+// loadConfig reads and parses path. If path is empty, it looks for one of
+// defaultConfigNames in the current directory. Finding none of them is not
+// an error: it returns an empty Config so the caller falls back entirely to
+// CLI flags. An explicitly given path that cannot be read is an error.
+func loadConfig(path string) (*Config, error) {
+	if path == "" {
+		for _, name := range defaultConfigNames {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+	}
+
+	cfg := &Config{}
+
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		err = yaml.Unmarshal(data, cfg)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
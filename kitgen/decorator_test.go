@@ -0,0 +1,159 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+// parseDecl parses src (a single file's worth of Go source) and returns its
+// comment map together with the *ast.GenDecl/*ast.TypeSpec for the first type
+// declaration found, mirroring how Generator builds these for a real file.
+func parseDecl(t *testing.T, src string) (ast.CommentMap, *ast.GenDecl, *ast.TypeSpec) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, "src.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parsing source: %s", err)
+	}
+
+	cmap := ast.NewCommentMap(fset, file, file.Comments)
+
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+
+		return cmap, genDecl, genDecl.Specs[0].(*ast.TypeSpec)
+	}
+
+	t.Fatalf("no type declaration found in source")
+
+	return nil, nil, nil
+}
+
+func TestDecoratorsForLoneDeclarationNotDoubleParsed(t *testing.T) {
+	const src = `package p
+
+// +kit:endpoint method=GET
+type Foo interface{}
+`
+
+	cmap, decl, spec := parseDecl(t, src)
+
+	r := &TypeRender{cmap: cmap}
+
+	decorators := r.decoratorsFor(decl, spec)
+
+	invocations := decorators["endpoint"]
+	if len(invocations) != 1 {
+		t.Fatalf("len(invocations) = %d, want 1 (got duplicate parse of the doc comment)", len(invocations))
+	}
+
+	if invocations[0].Options["method"] != "GET" {
+		t.Errorf("Options[method] = %q, want %q", invocations[0].Options["method"], "GET")
+	}
+}
+
+func TestDecoratorsForGroupedDeclaration(t *testing.T) {
+	const src = `package p
+
+type (
+	// +kit:endpoint method=POST
+	Foo interface{}
+)
+`
+
+	cmap, decl, spec := parseDecl(t, src)
+
+	r := &TypeRender{cmap: cmap}
+
+	decorators := r.decoratorsFor(decl, spec)
+
+	invocations := decorators["endpoint"]
+	if len(invocations) != 1 {
+		t.Fatalf("len(invocations) = %d, want 1", len(invocations))
+	}
+
+	if invocations[0].Options["method"] != "POST" {
+		t.Errorf("Options[method] = %q, want %q", invocations[0].Options["method"], "POST")
+	}
+}
+
+func TestDecoratorsForAppliesTemplateOverride(t *testing.T) {
+	const src = `package p
+
+// +kit:endpoint method=GET
+type Foo interface{}
+`
+
+	cmap, decl, spec := parseDecl(t, src)
+
+	r := &TypeRender{cmap: cmap, decoratorOverrides: map[string]string{"endpoint": "custom.tmpl"}}
+
+	decorators := r.decoratorsFor(decl, spec)
+
+	if got := decorators["endpoint"][0].Template; got != "custom.tmpl" {
+		t.Errorf("Template = %q, want %q", got, "custom.tmpl")
+	}
+}
+
+func TestParseDecoratorsMultiLineOptions(t *testing.T) {
+	const src = `package p
+
+type (
+	// +kit:generate mock
+	// receiver=m
+	// package=mocks
+	Foo interface{}
+)
+`
+
+	cmap, _, spec := parseDecl(t, src)
+
+	decorators := parseDecorators(cmap[spec])
+
+	invocations := decorators["generate"]
+	if len(invocations) != 1 {
+		t.Fatalf("len(invocations) = %d, want 1", len(invocations))
+	}
+
+	dec := invocations[0]
+
+	if dec.Template != "mock" {
+		t.Errorf("Template = %q, want %q", dec.Template, "mock")
+	}
+
+	if dec.Options["receiver"] != "m" || dec.Options["package"] != "mocks" {
+		t.Errorf("Options = %+v, want receiver=m package=mocks", dec.Options)
+	}
+}
+
+func TestParseDecoratorsInlineOptions(t *testing.T) {
+	const src = `package p
+
+type (
+	// +kit:endpoint method=POST path=/users
+	Foo interface{}
+)
+`
+
+	cmap, _, spec := parseDecl(t, src)
+
+	decorators := parseDecorators(cmap[spec])
+
+	invocations := decorators["endpoint"]
+	if len(invocations) != 1 {
+		t.Fatalf("len(invocations) = %d, want 1", len(invocations))
+	}
+
+	dec := invocations[0]
+
+	if dec.Options["method"] != "POST" || dec.Options["path"] != "/users" {
+		t.Errorf("Options = %+v, want method=POST path=/users", dec.Options)
+	}
+}
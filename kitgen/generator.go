@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// kitCommentPrefix marks a `+kit:` decorator comment, once its leading
+// "//" and surrounding whitespace have been trimmed.
+const kitCommentPrefix = "+kit:"
+
+// loadMode is the set of information the loader needs from go/packages in
+// order to resolve decorated interfaces through go/types instead of
+// hand-walking the AST.
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+	packages.NeedTypes | packages.NeedTypesInfo | packages.NeedDeps
+
+// Generator holds the loaded, type-checked package(s) and drives generation
+// for one or more decorated types.
+type Generator struct {
+	dir  string
+	pkgs []*packages.Package
+
+	// models maps a user-defined name, as used from a decorator's
+	// options, to the Go type templates should render for it - the
+	// models: section of kit.yaml.
+	models map[string]string
+}
+
+// parse loads the package(s) named by patterns (a directory, or a list of
+// files belonging to a single package) with full type information, so that
+// decorated interfaces can be resolved through go/types rather than by
+// hand-walking the AST.
+func (g *Generator) parse(patterns []string) error {
+	cfg := &packages.Config{Mode: loadMode}
+
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return fmt.Errorf("loading packages: %s", err)
+	}
+
+	if packages.PrintErrors(pkgs) > 0 {
+		return fmt.Errorf("packages contain errors")
+	}
+
+	if len(pkgs) == 0 {
+		return fmt.Errorf("no packages found for %v", patterns)
+	}
+
+	g.pkgs = pkgs
+	g.dir = filepath.Dir(pkgs[0].GoFiles[0])
+
+	return nil
+}
+
+// lookupInterface finds the named interface type amongst the loaded
+// packages, returning the package it was declared in alongside the
+// resolved *types.Interface.
+func (g *Generator) lookupInterface(typeName string) (*packages.Package, *types.Interface) {
+	for _, pkg := range g.pkgs {
+		obj := pkg.Types.Scope().Lookup(typeName)
+		if obj == nil {
+			continue
+		}
+
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+
+		if intf, ok := named.Underlying().(*types.Interface); ok {
+			return pkg, intf
+		}
+	}
+
+	return nil, nil
+}
+
+// generateHeader emits the generated file's package clause.
+func (g *Generator) generateHeader(r *Render) {
+	r.Printf("// Code generated by kitgen. DO NOT EDIT.\n\n")
+
+	if len(g.pkgs) > 0 {
+		r.Printf("package %s\n\n", g.pkgs[0].Name)
+	}
+}
+
+// generateType runs every decorator attached to typeCfg.Name, applying
+// typeCfg's per-decorator template overrides.
+func (g *Generator) generateType(typeCfg TypeConfig, r *Render) {
+	pkg, intf := g.lookupInterface(typeCfg.Name)
+	if pkg == nil {
+		return
+	}
+
+	currentImports = r.Imports
+
+	for _, file := range pkg.Syntax {
+		tr := &TypeRender{
+			name:               typeCfg.Name,
+			pkg:                pkg,
+			intf:               intf,
+			cmap:               ast.NewCommentMap(pkg.Fset, file, file.Comments),
+			decoratorOverrides: typeCfg.Decorators,
+			models:             g.models,
+		}
+
+		ast.Inspect(file, tr.visit)
+
+		r.Append(tr.render())
+	}
+}
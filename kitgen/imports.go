@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Imports tracks the set of packages a single generated file references,
+// assigning each a conflict-free alias. It mirrors the helper gqlgen
+// threads through its codegen templates: templates call the ref/lookup/
+// reserve funcs instead of writing import paths by hand, and the
+// accumulated set is spliced into the file's import block once every type
+// has rendered.
+type Imports struct {
+	byPath  map[string]string // import path -> alias
+	byAlias map[string]string // alias -> import path
+}
+
+// NewImports returns an empty import set.
+func NewImports() *Imports {
+	return &Imports{
+		byPath:  map[string]string{},
+		byAlias: map[string]string{},
+	}
+}
+
+// Reserve assigns alias to path, disambiguating with a numeric suffix if
+// alias is already taken by a different path. It returns the alias that was
+// actually assigned, and is idempotent for a path already reserved.
+func (im *Imports) Reserve(path, alias string) string {
+	if existing, ok := im.byPath[path]; ok {
+		return existing
+	}
+
+	if alias == "" {
+		alias = path[strings.LastIndex(path, "/")+1:]
+	}
+
+	candidate := alias
+
+	for i := 2; ; i++ {
+		if _, taken := im.byAlias[candidate]; !taken {
+			break
+		}
+
+		candidate = fmt.Sprintf("%s%d", alias, i)
+	}
+
+	im.byPath[path] = candidate
+	im.byAlias[candidate] = path
+
+	return candidate
+}
+
+// Lookup returns the alias reserved for path, reserving the package's base
+// name as a default alias if it hasn't been seen yet.
+func (im *Imports) Lookup(path string) string {
+	if alias, ok := im.byPath[path]; ok {
+		return alias
+	}
+
+	return im.Reserve(path, "")
+}
+
+// Ref splits a "pkg/path.Type" reference, reserves an alias for pkg/path,
+// and returns the correctly qualified selector, e.g. "alias.Type".
+func (im *Imports) Ref(qualifiedType string) string {
+	i := strings.LastIndex(qualifiedType, ".")
+	if i < 0 {
+		return qualifiedType
+	}
+
+	path, name := qualifiedType[:i], qualifiedType[i+1:]
+
+	return im.Lookup(path) + "." + name
+}
+
+// Block renders the accumulated imports as a parenthesized import block,
+// sorted by path so the generated output is deterministic.
+func (im *Imports) Block() string {
+	if len(im.byPath) == 0 {
+		return ""
+	}
+
+	paths := make([]string, 0, len(im.byPath))
+	for path := range im.byPath {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	var b strings.Builder
+
+	b.WriteString("import (\n")
+
+	for _, path := range paths {
+		alias := im.byPath[path]
+
+		if alias == path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(&b, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&b, "\t%s %q\n", alias, path)
+		}
+	}
+
+	b.WriteString(")\n")
+
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportsLookupReservesDefaultAlias(t *testing.T) {
+	im := NewImports()
+
+	if alias := im.Lookup("io"); alias != "io" {
+		t.Errorf("Lookup(io) = %q, want %q", alias, "io")
+	}
+
+	// A second lookup of the same path must return the same alias.
+	if alias := im.Lookup("io"); alias != "io" {
+		t.Errorf("second Lookup(io) = %q, want %q", alias, "io")
+	}
+}
+
+func TestImportsReserveDisambiguatesCollidingAlias(t *testing.T) {
+	im := NewImports()
+
+	first := im.Reserve("example.com/a/logging", "")
+	second := im.Reserve("example.com/b/logging", "")
+
+	if first == second {
+		t.Fatalf("two different paths both got alias %q", first)
+	}
+
+	if first != "logging" {
+		t.Errorf("first alias = %q, want %q", first, "logging")
+	}
+
+	if second != "logging2" {
+		t.Errorf("second alias = %q, want %q", second, "logging2")
+	}
+}
+
+func TestImportsReserveIsIdempotentForSamePath(t *testing.T) {
+	im := NewImports()
+
+	first := im.Reserve("io", "myio")
+	second := im.Reserve("io", "ignored")
+
+	if first != second {
+		t.Errorf("Reserve(io) returned %q then %q for the same path", first, second)
+	}
+}
+
+func TestImportsRef(t *testing.T) {
+	im := NewImports()
+
+	if got := im.Ref("io.Reader"); got != "io.Reader" {
+		t.Errorf("Ref(io.Reader) = %q, want %q", got, "io.Reader")
+	}
+
+	if got := im.Ref("Reader"); got != "Reader" {
+		t.Errorf("Ref with no package qualifier should pass through unchanged, got %q", got)
+	}
+}
+
+func TestImportsBlock(t *testing.T) {
+	im := NewImports()
+
+	if block := im.Block(); block != "" {
+		t.Errorf("Block() on an empty Imports = %q, want empty", block)
+	}
+
+	im.Reserve("io", "")
+	im.Reserve("example.com/a/logging", "")
+	im.Reserve("example.com/b/logging", "")
+
+	block := im.Block()
+
+	for _, want := range []string{`"io"`, `"example.com/a/logging"`, `logging2 "example.com/b/logging"`} {
+		if !strings.Contains(block, want) {
+			t.Errorf("Block() = %q, missing %q", block, want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadTemplateResolvesUserTemplateDir guards against loadTemplate
+// double-joining the embedded tree's "/templates" prefix onto a
+// -template-dir/template_dirs entry: a user's template-dir is itself the
+// templates root, so "templates/endpoint/GET.tmpl" (the layout mirroring
+// the embedded tree) must resolve without nesting an extra "templates/"
+// folder inside it.
+func TestLoadTemplateResolvesUserTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "endpoint"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "endpoint", "GET.tmpl"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := templateDirs
+	templateDirs = []string{dir}
+
+	defer func() { templateDirs = old }()
+
+	tmpl, err := loadTemplate("/templates/endpoint/GET.tmpl")
+	if err != nil {
+		t.Fatalf("loadTemplate: %s", err)
+	}
+
+	if tmpl == nil {
+		t.Fatal("loadTemplate returned a nil template with no error")
+	}
+}
@@ -9,28 +9,55 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	kit "github.com/flier/kit"
 )
 
+// templateDirFlag collects repeated -template-dir flags, in the order
+// given on the command line.
+type templateDirFlag []string
+
+func (f *templateDirFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *templateDirFlag) Set(value string) error {
+	*f = append(*f, value)
+
+	return nil
+}
+
 var (
-	appName   = filepath.Base(os.Args[0])
-	showHelp  = flag.Bool("help", false, "show usage")
-	debugMode = flag.Bool("debug", false, "debug mode")
-	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
-	suffix    = flag.String("suffix", "kit", "output file suffix in <type>_<suffix>.go")
-	output    = flag.String("output", "", "output file name (default \"<src dir>/<type>_<suffix>.go\")")
+	appName          = filepath.Base(os.Args[0])
+	showHelp         = flag.Bool("help", false, "show usage")
+	debugMode        = flag.Bool("debug", false, "debug mode")
+	typeNames        = flag.String("type", "", "comma-separated list of type names; overrides the types: section of the config file")
+	suffix           = flag.String("suffix", defaultSuffix, "output file suffix in <type>_<suffix>.go")
+	output           = flag.String("output", "", "output file name (default \"<src dir>/<type>_<suffix>.go\")")
+	configFile       = flag.String("config", "", "path to kit.yaml or .kit.toml (default: autodetect in current directory)")
+	check            = flag.Bool("check", false, "verify the on-disk output is up-to-date instead of writing it; exits non-zero with a diff otherwise")
+	templateDirFlags = templateDirFlag{}
 )
 
+func init() {
+	flag.Var(&templateDirFlags, "template-dir", "additional template root searched before the built-in templates (may be repeated)")
+}
+
 // Usage is a replacement usage function for the flags package.
 func Usage() {
 	fmt.Fprintf(os.Stderr, "Usage:\n")
 	fmt.Fprintf(os.Stderr, "  %s [flags] -type T [directory]\n", appName)
 	fmt.Fprintf(os.Stderr, "  %s [flags[ -type T files... # Must be a single package\n", appName)
+	fmt.Fprintf(os.Stderr, "  %s init                      # write a starter kit.yaml\n", appName)
+	fmt.Fprintf(os.Stderr, "  %s -check [flags] -type T    # fail if the output is stale\n", appName)
+	fmt.Fprintf(os.Stderr, "  %s diff [flags] -type T      # same as -check\n", appName)
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "Flags:\n")
 	flag.PrintDefaults()
 }
 
-func parseCmdLine() []string {
+// parseCmdLine merges the kit.yaml/.kit.toml config (if any) with the CLI
+// flags, which always take precedence, and returns the resolved config
+// together with the packages/files to load.
+func parseCmdLine() (*Config, []string) {
 	flag.Usage = Usage
 	flag.Parse()
 
@@ -39,18 +66,49 @@ func parseCmdLine() []string {
 		os.Exit(0)
 	}
 
-	if len(*typeNames) == 0 {
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("loading config: %s", err)
+	}
+
+	if len(*typeNames) > 0 {
+		cfg.Types = nil
+
+		for _, name := range strings.Split(*typeNames, ",") {
+			cfg.Types = append(cfg.Types, TypeConfig{Name: name})
+		}
+	}
+
+	if len(cfg.Types) == 0 {
 		flag.Usage()
 		os.Exit(2)
 	}
 
+	suffixSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "suffix" {
+			suffixSet = true
+		}
+	})
+
+	if suffixSet {
+		cfg.Suffix = *suffix
+	} else if cfg.Suffix == "" {
+		cfg.Suffix = defaultSuffix
+	}
+
 	// We accept either one directory or a list of files. Which do we have?
-	if files := flag.Args(); len(files) == 0 {
+	files := flag.Args()
+	if len(files) == 0 {
+		files = cfg.Packages
+	}
+
+	if len(files) == 0 {
 		// Default: process whole package in current directory.
-		return []string{"."}
-	} else {
-		return files
+		files = []string{"."}
 	}
+
+	return cfg, files
 }
 
 // isDirectory reports whether the named file is a directory.
@@ -66,12 +124,31 @@ func main() {
 	log.SetFlags(0)
 	log.SetPrefix(appName + ": ")
 
-	files := parseCmdLine()
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		runInit()
+		return
+	}
+
+	// `kit diff` is just shorthand for `kit -check`; strip the subcommand
+	// so the rest of the flags still parse normally.
+	wantCheck := false
+
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		wantCheck = true
+	}
+
+	cfg, files := parseCmdLine()
 
-	types := strings.Split(*typeNames, ",")
+	wantCheck = wantCheck || *check
+
+	templateDirs = append([]string(templateDirFlags), cfg.TemplateDirs...)
+	registerPluginFuncs()
 
 	generator := new(Generator)
+	generator.models = cfg.Models
 	render := new(Render)
+	render.Imports = NewImports()
 
 	if err := generator.parse(files); err != nil {
 		log.Fatalf("writing output: %s", err)
@@ -80,26 +157,36 @@ func main() {
 	generator.generateHeader(render)
 
 	// Run generate for each type.
-	for _, typeName := range types {
-		generator.generateType(typeName, render)
+	for _, typeCfg := range cfg.Types {
+		generator.generateType(typeCfg, render)
 	}
 
 	// Format the output.
 	if src, err := render.format(); err != nil {
 		log.Fatalf("formating source: %s", err)
 	} else {
-		// Write to file.
-		if outputName := *output; outputName == "-" {
-			os.Stdout.WriteString(string(src))
-		} else {
-			if outputName == "" {
-				baseName := fmt.Sprintf("%s_%s.go", types[0], *suffix)
-				outputName = filepath.Join(generator.dir, strings.ToLower(baseName))
+		for _, p := range kit.Plugins() {
+			if pp, ok := p.(kit.PostProcessor); ok {
+				src = pp.PostProcess(src)
 			}
+		}
 
-			if err := ioutil.WriteFile(outputName, src, 0644); err != nil {
-				log.Fatalf("writing output: %s", err)
-			}
+		outputName := *output
+		if outputName == "" {
+			outputName = cfg.Types[0].Output
+		}
+
+		if outputName == "" {
+			baseName := fmt.Sprintf("%s_%s.go", cfg.Types[0].Name, cfg.Suffix)
+			outputName = filepath.Join(generator.dir, strings.ToLower(baseName))
+		}
+
+		if wantCheck {
+			checkMode(outputName, src)
+		} else if outputName == "-" {
+			os.Stdout.WriteString(string(src))
+		} else if err := ioutil.WriteFile(outputName, src, 0644); err != nil {
+			log.Fatalf("writing output: %s", err)
 		}
 	}
 }
@@ -2,11 +2,11 @@ package main
 
 import (
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"text/template"
@@ -14,6 +14,11 @@ import (
 	"go/ast"
 	"go/format"
 	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+
+	kit "github.com/flier/kit"
 )
 
 var (
@@ -23,9 +28,65 @@ var (
 		"uncapitalize": func(s string) string { return strings.ToLower(s[:1]) + s[1:] },
 		"upper":        func(s string) string { return strings.ToUpper(s) },
 		"lower":        func(s string) string { return strings.ToLower(s) },
+		"ref": func(qualifiedType string) string {
+			if currentImports == nil {
+				return qualifiedType
+			}
+
+			return currentImports.Ref(qualifiedType)
+		},
+		"lookup": func(path string) string {
+			if currentImports == nil {
+				return path
+			}
+
+			return currentImports.Lookup(path)
+		},
+		"reserve": func(path, alias string) string {
+			if currentImports == nil {
+				return alias
+			}
+
+			return currentImports.Reserve(path, alias)
+		},
 	})
+
+	// currentImports is the Imports set for whichever Render is presently
+	// executing templates, so decorator templates can call ref/lookup/
+	// reserve without threading an *Imports through every template's data.
+	// It is only valid for the duration of one file render.
+	currentImports *Imports
+
+	// templateDirs are additional on-disk template roots, searched in
+	// order before the embedded templates, so a team can override or add
+	// decorators without patching kitgen itself.
+	templateDirs []string
 )
 
+// mutateSpecs runs specs through every registered kit.SpecMutator, in
+// registration order, before a decorator template sees them.
+func mutateSpecs(specs []map[string]interface{}) []map[string]interface{} {
+	for _, p := range kit.Plugins() {
+		if m, ok := p.(kit.SpecMutator); ok {
+			specs = m.MutateSpecs(specs)
+		}
+	}
+
+	return specs
+}
+
+// registerPluginFuncs merges every registered kit.FuncsPlugin's template
+// funcs into topLevelTemplate. It must run before the first loadTemplate
+// call so decorator templates can reference plugin-contributed funcs like
+// "snake" or "pluralize".
+func registerPluginFuncs() {
+	for _, p := range kit.Plugins() {
+		if fp, ok := p.(kit.FuncsPlugin); ok {
+			topLevelTemplate = topLevelTemplate.Funcs(fp.TemplateFuncs())
+		}
+	}
+}
+
 func loadTemplate(name string) (*template.Template, error) {
 	log.Printf("loading template: %s", name)
 
@@ -35,6 +96,22 @@ func loadTemplate(name string) (*template.Template, error) {
 		}
 	}
 
+	// name is always rooted at the embedded tree's "/templates" prefix
+	// (see the loadTemplate call in visit()); a user's -template-dir/
+	// template_dirs entry is itself the templates root, e.g.
+	// "<dir>/endpoint/GET.tmpl", so that prefix must be stripped before
+	// joining with dir.
+	relName := strings.TrimPrefix(name, "/templates/")
+
+	for _, dir := range templateDirs {
+		data, err := ioutil.ReadFile(filepath.Join(dir, relName))
+		if err != nil {
+			continue
+		}
+
+		return topLevelTemplate.Parse(string(data))
+	}
+
 	if f, err := FS(*debugMode).Open(name); err != nil {
 		return nil, fmt.Errorf("fail to open template, %s", err)
 	} else if data, err := ioutil.ReadAll(f); err != nil {
@@ -47,7 +124,8 @@ func loadTemplate(name string) (*template.Template, error) {
 }
 
 type Render struct {
-	buf bytes.Buffer // Accumulated output.
+	buf     bytes.Buffer // Accumulated output.
+	Imports *Imports     // Packages referenced via the ref/lookup/reserve template funcs.
 }
 
 func (r *Render) Append(text string) *Render {
@@ -60,21 +138,90 @@ func (r *Render) Printf(format string, args ...interface{}) {
 	fmt.Fprintf(&r.buf, format, args...)
 }
 
-// format returns the gofmt-ed contents of the Generator's buffer.
+// format splices the imports collected while rendering into the file's
+// import block, then returns the gofmt-ed contents of the Render's buffer.
 func (r *Render) format() ([]byte, error) {
-	if src, err := format.Source(r.buf.Bytes()); err != nil {
-		return r.buf.Bytes(), err
+	src := r.buf.Bytes()
+
+	if r.Imports != nil {
+		if block := r.Imports.Block(); block != "" {
+			src = spliceImports(src, block)
+		}
+	}
+
+	if formatted, err := format.Source(src); err != nil {
+		return src, err
 	} else {
-		return src, nil
+		return formatted, nil
 	}
 }
 
+// spliceImports inserts block immediately after the generated file's
+// "package foo" clause.
+func spliceImports(src []byte, block string) []byte {
+	marker := []byte("package ")
+
+	idx := bytes.Index(src, marker)
+	if idx < 0 {
+		return src
+	}
+
+	end := bytes.IndexByte(src[idx:], '\n')
+	if end < 0 {
+		return src
+	}
+
+	end += idx + 1
+
+	out := make([]byte, 0, len(src)+len(block)+1)
+	out = append(out, src[:end]...)
+	out = append(out, '\n')
+	out = append(out, block...)
+	out = append(out, src[end:]...)
+
+	return out
+}
+
 type TypeRender struct {
-	name  string
+	name string
+	pkg  *packages.Package
+	intf *types.Interface
+	cmap ast.CommentMap
+
+	// decoratorOverrides maps a decorator name to the template it should
+	// use for this type, overriding the template named in its `+kit:`
+	// comment - the types[].decorators section of kit.yaml.
+	decoratorOverrides map[string]string
+
+	// models maps a user-defined name, as used from a decorator's
+	// options, to the Go type templates should render for it.
+	models map[string]string
+
 	buf   bytes.Buffer
 	stack []token.Pos
 }
 
+// templateData is what a decorator template's top-level "." resolves to:
+// the rendered type(s), the invoking decorator's positional Params and
+// key=value Options, and the config file's models: name-to-Go-type mapping.
+type templateData struct {
+	Types   []map[string]interface{}
+	Params  []string
+	Options map[string]string
+	Models  map[string]string
+}
+
+// qualifier returns the types.Qualifier used when stringifying types found
+// on the rendered interface, so that identifiers from r.pkg are left bare
+// and everything else is rendered as "pkg.Type".
+func (r *TypeRender) qualifier() types.Qualifier {
+	if r.pkg == nil {
+		return nil
+	}
+
+	return types.RelativeTo(r.pkg.Types)
+}
+
 func (r *TypeRender) render() string {
 	return r.buf.String()
 }
@@ -106,31 +253,48 @@ func (r *TypeRender) visit(node ast.Node) bool {
 	}
 
 	if decl, ok := node.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
-		if decorators := r.parseDecorators(decl.Doc); len(decorators) > 0 {
+		for _, spec := range decl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok || typeSpec.Name == nil || typeSpec.Name.Name != r.name {
+				continue
+			}
+
+			decorators := r.decoratorsFor(decl, typeSpec)
+			if len(decorators) == 0 {
+				continue
+			}
+
 			if *debugMode {
 				log.Printf("found decorators: %v", decorators)
 			}
 
-			if specs := r.parseSpecs(decl.Specs); len(specs) > 0 {
-				if *debugMode {
-					if b, err := json.Marshal(specs); err != nil {
-						log.Fatal(err)
-					} else {
-						var out bytes.Buffer
+			specs := r.parseSpecs(decl.Specs)
+			if len(specs) == 0 {
+				continue
+			}
 
-						json.Indent(&out, b, ">", "\t")
+			specs = mutateSpecs(specs)
 
-						log.Printf("found specs: %s", out.String())
-					}
+			if *debugMode {
+				if b, err := json.Marshal(specs); err != nil {
+					log.Fatal(err)
+				} else {
+					var out bytes.Buffer
+
+					json.Indent(&out, b, ">", "\t")
+
+					log.Printf("found specs: %s", out.String())
 				}
+			}
+
+			for name, invocations := range decorators {
+				for _, dec := range invocations {
+					data := &templateData{Types: specs, Params: dec.Params, Options: dec.Options, Models: r.models}
 
-				for decorator, names := range decorators {
-					for _, name := range names {
-						if tmpl, err := loadTemplate(fmt.Sprintf("/templates/%s/%s.tmpl", decorator, name)); err != nil {
-							log.Fatal(err)
-						} else if err := tmpl.Execute(&r.buf, specs); err != nil {
-							log.Fatal(err)
-						}
+					if tmpl, err := loadTemplate(fmt.Sprintf("/templates/%s/%s.tmpl", name, dec.Template)); err != nil {
+						log.Fatal(err)
+					} else if err := tmpl.Execute(&r.buf, data); err != nil {
+						log.Fatal(err)
 					}
 				}
 			}
@@ -140,34 +304,79 @@ func (r *TypeRender) visit(node ast.Node) bool {
 	return true
 }
 
-func (r *TypeRender) parseDecorators(comments *ast.CommentGroup) (decorators map[string][]string) {
-	if comments != nil {
-		for _, comment := range comments.List {
-			if strings.HasPrefix(comment.Text, kitCommentPrefix) {
-				line := comment.Text[len(kitCommentPrefix):]
+// Decorator is one `+kit:name ...` invocation found on a declaration. A
+// single invocation may span several comment lines: the first carries the
+// decorator name and an optional template/positional argument, and any
+// further continuation lines - more `key=value` fields, one comment line
+// each - are folded into Options.
+type Decorator struct {
+	Name     string
+	Template string
+	Params   []string
+	Options  map[string]string
+}
 
-				parts := kitCommentSep.Split(line, 2)
+// decoratorsFor collects every `+kit:` decorator attached to typeSpec,
+// whether it was declared on a lone `type Foo interface{}` (where
+// ast.NewCommentMap files the doc comment under r.cmap[decl]) or inside a
+// grouped `type ( ... )` block (where it attaches to the individual
+// TypeSpec instead). r.cmap already covers both cases, so decl.Doc itself
+// must not be appended again - it's the same *ast.CommentGroup as
+// r.cmap[decl] in the lone-declaration case, and appending it a second time
+// double-parses it, running every decorator on it twice.
+func (r *TypeRender) decoratorsFor(decl *ast.GenDecl, typeSpec *ast.TypeSpec) map[string][]*Decorator {
+	var groups []*ast.CommentGroup
+
+	groups = append(groups, r.cmap[decl]...)
+	groups = append(groups, r.cmap[typeSpec]...)
+
+	decorators := parseDecorators(groups)
+
+	for name, invocations := range decorators {
+		template, ok := r.decoratorOverrides[name]
+		if !ok {
+			continue
+		}
 
-				name := strings.TrimSpace(parts[0])
-				var params []string
+		for _, dec := range invocations {
+			dec.Template = template
+		}
+	}
 
-				if len(parts) > 1 {
-					r := csv.NewReader(strings.NewReader(parts[1]))
-					r.Comment = '#'
-					r.TrimLeadingSpace = true
+	return decorators
+}
 
-					if fields, err := r.Read(); err == nil {
-						params = fields
-					}
-				}
+// parseDecorators scans groups for `+kit:` comments, building one
+// *Decorator per invocation. Comment lines right below a `+kit:` line that
+// are themselves entirely `key=value` fields are treated as continuations
+// of that invocation and merged into its Options, rather than being
+// mistaken for a new, bare decorator.
+func parseDecorators(groups []*ast.CommentGroup) (decorators map[string][]*Decorator) {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+
+		var current *Decorator
+
+		for _, comment := range group.List {
+			text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+
+			switch {
+			case strings.HasPrefix(text, kitCommentPrefix):
+				current = parseDecoratorHeader(text[len(kitCommentPrefix):])
 
 				if decorators == nil {
-					decorators = map[string][]string{name: params}
-				} else if _, exists := decorators[name]; exists && params != nil {
-					decorators[name] = append(decorators[name], params...)
-				} else {
-					decorators[name] = params
+					decorators = map[string][]*Decorator{}
 				}
+
+				decorators[current.Name] = append(decorators[current.Name], current)
+
+			case current != nil && isOptionLine(text):
+				mergeOptions(current, text)
+
+			default:
+				current = nil
 			}
 		}
 	}
@@ -175,56 +384,136 @@ func (r *TypeRender) parseDecorators(comments *ast.CommentGroup) (decorators map
 	return
 }
 
-func (r *TypeRender) parseSpecs(specs []ast.Spec) (types []map[string]interface{}) {
+// parseDecoratorHeader parses the text following "+kit:" on a decorator's
+// first comment line: a name, an optional positional template argument, and
+// any inline `key=value` options.
+func parseDecoratorHeader(line string) *Decorator {
+	d := &Decorator{Options: map[string]string{}}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return d
+	}
+
+	d.Name = fields[0]
+	d.Template = d.Name
+
+	for _, field := range fields[1:] {
+		if key, value, ok := splitOption(field); ok {
+			d.Options[key] = value
+
+			continue
+		}
+
+		d.Params = append(d.Params, field)
+
+		if d.Template == d.Name {
+			d.Template = field
+		}
+	}
+
+	return d
+}
+
+// isOptionLine reports whether text is entirely `key=value` fields, i.e. a
+// continuation line for the decorator above it rather than a new comment.
+func isOptionLine(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+
+	for _, field := range fields {
+		if _, _, ok := splitOption(field); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mergeOptions(d *Decorator, text string) {
+	for _, field := range strings.Fields(text) {
+		if key, value, ok := splitOption(field); ok {
+			d.Options[key] = value
+		}
+	}
+}
+
+// splitOption splits a "key=value" field. ok is false for anything else,
+// including a bare "=value" with no key.
+func splitOption(field string) (key, value string, ok bool) {
+	i := strings.Index(field, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+
+	return field[:i], field[i+1:], true
+}
+
+// parseSpecs resolves the matching TypeSpec through r.intf (already
+// type-checked by the Generator) rather than re-deriving it from the AST, so
+// that methods using standard-library types, pointers, slices, maps,
+// channels, func types, or (Go 1.18+) type parameters are all handled
+// uniformly instead of panicking on anything but a bare *ast.Ident.
+func (r *TypeRender) parseSpecs(specs []ast.Spec) (descs []map[string]interface{}) {
+	if r.intf == nil {
+		return
+	}
+
 	for _, spec := range specs {
-		if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.Name != nil && typeSpec.Name.Name == r.name {
-			if intfType, ok := typeSpec.Type.(*ast.InterfaceType); ok {
-				var methods []interface{}
-
-				for _, method := range intfType.Methods.List {
-					if funcType, ok := method.Type.(*ast.FuncType); ok {
-						methods = append(methods, map[string]interface{}{
-							"Name":    method.Names[0].Name,
-							"Params":  r.parseFieldList(funcType.Params),
-							"Results": r.parseFieldList(funcType.Results),
-						})
-					}
-				}
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok || typeSpec.Name == nil || typeSpec.Name.Name != r.name {
+			continue
+		}
 
-				types = append(types, map[string]interface{}{
-					"Name":    typeSpec.Name.Name,
-					"Methods": methods,
-				})
-			}
+		var methods []interface{}
+
+		for i := 0; i < r.intf.NumMethods(); i++ {
+			method := r.intf.Method(i)
+			sig := method.Type().(*types.Signature)
+
+			methods = append(methods, map[string]interface{}{
+				"Name":    method.Name(),
+				"Params":  r.parseTuple(sig.Params(), sig.Variadic()),
+				"Results": r.parseTuple(sig.Results(), false),
+			})
 		}
+
+		descs = append(descs, map[string]interface{}{
+			"Name":    typeSpec.Name.Name,
+			"Methods": methods,
+		})
 	}
 
 	return
 }
 
-func (r *TypeRender) parseFieldList(fields *ast.FieldList) (types []map[string]interface{}) {
-	i := 0
+// parseTuple normalizes a *types.Tuple (a method's params or results) into
+// one descriptor per entry, naming unnamed entries after their type the way
+// the previous AST-based parseFieldList did.
+func (r *TypeRender) parseTuple(tuple *types.Tuple, variadic bool) (descs []map[string]interface{}) {
+	if tuple == nil {
+		return
+	}
 
-	for _, field := range fields.List {
-		var names []string
+	qf := r.qualifier()
+	n := tuple.Len()
 
-		typeName := field.Type.(*ast.Ident).Name
+	for i := 0; i < n; i++ {
+		v := tuple.At(i)
 
-		if len(field.Names) > 0 {
-			for _, name := range field.Names {
-				names = append(names, strings.Title(name.Name))
-			}
+		name := v.Name()
+		if name == "" {
+			name = fmt.Sprintf("%s%d", strings.Title(typeLetter(v.Type())), i)
 		} else {
-			names = append(names, fmt.Sprintf("%s%d", strings.Title(typeName[:1]), i))
-			i += 1
+			name = strings.Title(name)
 		}
 
-		for _, name := range names {
-			types = append(types, map[string]interface{}{
-				"Name": name,
-				"Type": typeName,
-			})
-		}
+		descs = append(descs, map[string]interface{}{
+			"Name": name,
+			"Type": newTypeDescriptor(v.Type(), variadic && i == n-1, qf),
+		})
 	}
 
 	return
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEscLocalFSOpenIsRelativeToCwd guards against _escLocalFS.Open passing
+// an absolute-looking "/templates/..." name straight to os.Open, which
+// os.Open treats as rooted at the filesystem root rather than the current
+// directory, contradicting this type's own doc comment.
+func TestEscLocalFSOpenIsRelativeToCwd(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "templates", "endpoint"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "templates", "endpoint", "GET.tmpl"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Chdir(cwd)
+
+	f, err := _escLocal.Open("/templates/endpoint/GET.tmpl")
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+
+	defer f.Close()
+}
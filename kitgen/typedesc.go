@@ -0,0 +1,160 @@
+package main
+
+import (
+	"go/types"
+	"unicode"
+)
+
+// Kind classifies a resolved go/types.Type for template rendering, so that
+// templates can switch on `.Kind` instead of type-asserting go/ast
+// expressions themselves.
+type Kind int
+
+const (
+	KindBasic Kind = iota
+	KindNamed
+	KindPointer
+	KindSlice
+	KindArray
+	KindMap
+	KindChan
+	KindFunc
+	KindInterface
+	KindStruct
+	KindTypeParam
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindBasic:
+		return "basic"
+	case KindNamed:
+		return "named"
+	case KindPointer:
+		return "pointer"
+	case KindSlice:
+		return "slice"
+	case KindArray:
+		return "array"
+	case KindMap:
+		return "map"
+	case KindChan:
+		return "chan"
+	case KindFunc:
+		return "func"
+	case KindInterface:
+		return "interface"
+	case KindStruct:
+		return "struct"
+	case KindTypeParam:
+		return "typeparam"
+	default:
+		return "unknown"
+	}
+}
+
+// TypeDescriptor is a normalized, template-friendly view of a go/types.Type.
+// It lets templates render a parameter or result's signature without having
+// to type-switch over go/ast expressions, and without panicking on anything
+// other than a bare identifier.
+type TypeDescriptor struct {
+	Qualified string // fully qualified type string, e.g. "io.Reader"
+	Kind      Kind
+	Variadic  bool // true if this descriptor is the trailing "...T" parameter
+
+	Elem *TypeDescriptor // pointer/slice/array/chan element, or map value
+	Key  *TypeDescriptor // map key
+
+	Params  []*TypeDescriptor // func params
+	Results []*TypeDescriptor // func results
+
+	Constraints []*TypeDescriptor // type parameter constraints (Go 1.18+)
+}
+
+// newTypeDescriptor walks t, producing a normalized descriptor for it and
+// everything it's composed of.
+func newTypeDescriptor(t types.Type, variadic bool, qf types.Qualifier) *TypeDescriptor {
+	d := &TypeDescriptor{
+		Qualified: types.TypeString(t, qf),
+		Variadic:  variadic,
+	}
+
+	switch u := t.(type) {
+	case *types.Basic:
+		d.Kind = KindBasic
+	case *types.Pointer:
+		d.Kind = KindPointer
+		d.Elem = newTypeDescriptor(u.Elem(), false, qf)
+	case *types.Slice:
+		d.Kind = KindSlice
+		d.Elem = newTypeDescriptor(u.Elem(), false, qf)
+	case *types.Array:
+		d.Kind = KindArray
+		d.Elem = newTypeDescriptor(u.Elem(), false, qf)
+	case *types.Map:
+		d.Kind = KindMap
+		d.Key = newTypeDescriptor(u.Key(), false, qf)
+		d.Elem = newTypeDescriptor(u.Elem(), false, qf)
+	case *types.Chan:
+		d.Kind = KindChan
+		d.Elem = newTypeDescriptor(u.Elem(), false, qf)
+	case *types.Signature:
+		d.Kind = KindFunc
+		d.Params = tupleDescriptors(u.Params(), u.Variadic(), qf)
+		d.Results = tupleDescriptors(u.Results(), false, qf)
+	case *types.Interface:
+		d.Kind = KindInterface
+	case *types.Struct:
+		d.Kind = KindStruct
+	case *types.TypeParam:
+		d.Kind = KindTypeParam
+
+		if iface, ok := u.Constraint().Underlying().(*types.Interface); ok {
+			for i := 0; i < iface.NumEmbeddeds(); i++ {
+				d.Constraints = append(d.Constraints, newTypeDescriptor(iface.EmbeddedType(i), false, qf))
+			}
+		}
+	case *types.Named:
+		if _, ok := u.Underlying().(*types.Interface); ok {
+			d.Kind = KindInterface
+		} else {
+			d.Kind = KindNamed
+		}
+	default:
+		d.Kind = KindNamed
+	}
+
+	return d
+}
+
+// tupleDescriptors normalizes every entry of a *types.Tuple (a param or
+// result list), marking the last entry variadic when the enclosing
+// signature is variadic.
+func tupleDescriptors(tuple *types.Tuple, variadic bool, qf types.Qualifier) (descs []*TypeDescriptor) {
+	if tuple == nil {
+		return nil
+	}
+
+	n := tuple.Len()
+
+	for i := 0; i < n; i++ {
+		descs = append(descs, newTypeDescriptor(tuple.At(i).Type(), variadic && i == n-1, qf))
+	}
+
+	return
+}
+
+// typeLetter returns the leading letter of t's bare (unqualified) type name,
+// used to synthesize a name for an unnamed parameter or result, e.g. "io.Reader"
+// yields "r" the same way the old "*ast.Ident"-only parser used its Ident name.
+func typeLetter(t types.Type) string {
+	name := types.TypeString(t, func(*types.Package) string { return "" })
+
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			return string(unicode.ToLower(r))
+		}
+	}
+
+	return "x"
+}
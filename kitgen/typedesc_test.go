@@ -0,0 +1,92 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestNewTypeDescriptorBasic(t *testing.T) {
+	d := newTypeDescriptor(types.Typ[types.String], false, nil)
+
+	if d.Kind != KindBasic {
+		t.Errorf("Kind = %v, want KindBasic", d.Kind)
+	}
+
+	if d.Qualified != "string" {
+		t.Errorf("Qualified = %q, want %q", d.Qualified, "string")
+	}
+}
+
+func TestNewTypeDescriptorPointerSliceMap(t *testing.T) {
+	str := types.Typ[types.String]
+
+	ptr := newTypeDescriptor(types.NewPointer(str), false, nil)
+	if ptr.Kind != KindPointer || ptr.Elem.Kind != KindBasic {
+		t.Errorf("pointer descriptor = %+v", ptr)
+	}
+
+	slice := newTypeDescriptor(types.NewSlice(str), false, nil)
+	if slice.Kind != KindSlice || slice.Elem.Kind != KindBasic {
+		t.Errorf("slice descriptor = %+v", slice)
+	}
+
+	m := newTypeDescriptor(types.NewMap(str, types.Typ[types.Int]), false, nil)
+	if m.Kind != KindMap || m.Key.Kind != KindBasic || m.Elem.Qualified != "int" {
+		t.Errorf("map descriptor = %+v", m)
+	}
+
+	ch := newTypeDescriptor(types.NewChan(types.SendRecv, str), false, nil)
+	if ch.Kind != KindChan || ch.Elem.Kind != KindBasic {
+		t.Errorf("chan descriptor = %+v", ch)
+	}
+}
+
+func TestNewTypeDescriptorVariadic(t *testing.T) {
+	sig := types.NewSignatureType(nil, nil, nil,
+		types.NewTuple(types.NewVar(token.NoPos, nil, "args", types.NewSlice(types.Typ[types.String]))),
+		nil, true)
+
+	descs := tupleDescriptors(sig.Params(), sig.Variadic(), nil)
+	if len(descs) != 1 {
+		t.Fatalf("len(descs) = %d, want 1", len(descs))
+	}
+
+	if !descs[0].Variadic {
+		t.Errorf("Variadic = false, want true for the trailing param of a variadic signature")
+	}
+}
+
+func TestNewTypeDescriptorNamedInterface(t *testing.T) {
+	pkg := types.NewPackage("example.com/io", "io")
+	iface := types.NewInterfaceType(nil, nil).Complete()
+	name := types.NewTypeName(token.NoPos, pkg, "Reader", nil)
+	named := types.NewNamed(name, iface, nil)
+
+	d := newTypeDescriptor(named, false, nil)
+
+	if d.Kind != KindInterface {
+		t.Errorf("Kind = %v, want KindInterface for a named interface type", d.Kind)
+	}
+}
+
+func TestTypeLetter(t *testing.T) {
+	if got := typeLetter(types.NewSlice(types.Typ[types.String])); got != "s" {
+		t.Errorf("typeLetter([]string) = %q, want %q", got, "s")
+	}
+
+	if got := typeLetter(types.Typ[types.Int]); got != "i" {
+		t.Errorf("typeLetter(int) = %q, want %q", got, "i")
+	}
+}
+
+func TestTypeLetterNamedTypeFromAnotherPackage(t *testing.T) {
+	pkg := types.NewPackage("io", "io")
+	iface := types.NewInterfaceType(nil, nil).Complete()
+	name := types.NewTypeName(token.NoPos, pkg, "Reader", nil)
+	named := types.NewNamed(name, iface, nil)
+
+	if got := typeLetter(named); got != "r" {
+		t.Errorf("typeLetter(io.Reader) = %q, want %q (letter of the type name, not the package)", got, "r")
+	}
+}
@@ -0,0 +1,50 @@
+package kit
+
+import "text/template"
+
+// Plugin extends kitgen's code generation pipeline, modeled on gqlgen's
+// plugin system: a package registers a Plugin from its init() (typically
+// via a side-effect import added next to a `//go:generate kitgen` line)
+// before main runs, and kitgen applies every registered Plugin while
+// generating. Plugin alone only identifies the plugin; implement one of
+// the capability interfaces below to actually hook into generation.
+type Plugin interface {
+	// Name identifies the plugin.
+	Name() string
+}
+
+// FuncsPlugin lets a plugin contribute template funcs (e.g. "snake",
+// "pluralize") callable from any decorator template, without patching
+// kitgen's built-in topLevelTemplate directly.
+type FuncsPlugin interface {
+	Plugin
+	TemplateFuncs() template.FuncMap
+}
+
+// SpecMutator lets a plugin rewrite the specs produced for a decorated
+// type before templates render them.
+type SpecMutator interface {
+	Plugin
+	MutateSpecs(specs []map[string]interface{}) []map[string]interface{}
+}
+
+// PostProcessor lets a plugin rewrite the generated file's bytes after
+// gofmt, before kitgen writes them out.
+type PostProcessor interface {
+	Plugin
+	PostProcess(file []byte) []byte
+}
+
+var plugins []Plugin
+
+// Register adds p to the set of plugins kitgen applies during generation.
+// It's meant to be called from an init() in a side-effect import, the same
+// pattern gqlgen uses for its own plugins.
+func Register(p Plugin) {
+	plugins = append(plugins, p)
+}
+
+// Plugins returns every plugin registered so far.
+func Plugins() []Plugin {
+	return plugins
+}